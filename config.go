@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dewa55/updateAzureDNSrecords/records"
+	"gopkg.in/yaml.v3"
+)
+
+// Config maps a relative record name (e.g. "www" or "@") to the specs used to
+// build its record set(s). A name maps to a list, not a single Spec, so the
+// same name can publish more than one record type at once (e.g. A and AAAA
+// for dual-stack hosts). It is loaded from the file at AZURE_CONFIG_FILE, in
+// either YAML or JSON.
+type Config map[string][]records.Spec
+
+// loadConfig reads and parses the record config file at path. YAML is
+// assumed unless the file has a .json extension.
+func loadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	config := Config{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s as JSON: %w", path, err)
+		}
+		return config, nil
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s as YAML: %w", path, err)
+	}
+	return config, nil
+}
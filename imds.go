@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// defaultMetadataEndpoint is Azure's well-known Instance Metadata Service
+// address, reachable only from inside a VM's network. Mirrors the
+// defaultMetadataEndpoint lego's azure provider talks to for the same
+// purpose.
+const defaultMetadataEndpoint = "http://169.254.169.254"
+
+// imdsInstanceInfo is the subset of the IMDS instance document
+// (/metadata/instance) this tool needs: the subscription/resource group the
+// VM belongs to, and the first public IPv4 address found on its NICs (in the
+// order IMDS lists them), if any.
+type imdsInstanceInfo struct {
+	SubscriptionID string
+	ResourceGroup  string
+	PublicIPv4     string
+}
+
+type imdsInstanceDocument struct {
+	Compute struct {
+		SubscriptionID    string `json:"subscriptionId"`
+		ResourceGroupName string `json:"resourceGroupName"`
+	} `json:"compute"`
+	Network struct {
+		Interface []struct {
+			IPv4 struct {
+				IPAddress []struct {
+					PublicIPAddress string `json:"publicIpAddress"`
+				} `json:"ipAddress"`
+			} `json:"ipv4"`
+		} `json:"interface"`
+	} `json:"network"`
+}
+
+// fetchIMDSInstanceInfo queries IMDS for the current VM's compute and
+// network metadata.
+func fetchIMDSInstanceInfo() (*imdsInstanceInfo, error) {
+	var doc imdsInstanceDocument
+	if err := getIMDS("/metadata/instance?api-version=2021-02-01", &doc); err != nil {
+		return nil, err
+	}
+
+	info := &imdsInstanceInfo{
+		SubscriptionID: doc.Compute.SubscriptionID,
+		ResourceGroup:  doc.Compute.ResourceGroupName,
+	}
+	for _, iface := range doc.Network.Interface {
+		for _, addr := range iface.IPv4.IPAddress {
+			if addr.PublicIPAddress != "" {
+				info.PublicIPv4 = addr.PublicIPAddress
+				return info, nil
+			}
+		}
+	}
+	return info, nil
+}
+
+// getIMDS issues a GET against path on the metadata endpoint (AZURE_IMDS_ENDPOINT,
+// defaulting to defaultMetadataEndpoint) with the required Metadata: true
+// header, and decodes the JSON response into out.
+func getIMDS(path string, out interface{}) error {
+	endpoint := getEnvOrDefault("AZURE_IMDS_ENDPOINT", defaultMetadataEndpoint)
+	req, err := http.NewRequest(http.MethodGet, endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("IMDS request to %s failed with status %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/dewa55/updateAzureDNSrecords/pkg/acme"
+	"github.com/dewa55/updateAzureDNSrecords/records"
 )
 
 func fetchPublicIp() (string, error) {
-	public_ip_url := "https://api.ipify.org?format=text"
-	response, err := http.Get(public_ip_url)
+	public_ip_url := getEnvOrDefault("AZURE_IPV4_ENDPOINT", "https://api.ipify.org?format=text")
+	return fetchIP(public_ip_url)
+}
+
+func fetchPublicIPv6() (string, error) {
+	public_ip_url := getEnvOrDefault("AZURE_IPV6_ENDPOINT", "https://api6.ipify.org?format=text")
+	return fetchIP(public_ip_url)
+}
+
+func fetchIP(url string) (string, error) {
+	response, err := http.Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -27,6 +41,14 @@ func fetchPublicIp() (string, error) {
 	return string(ip), nil
 }
 
+func getEnvOrDefault(name string, defaultValue string) string {
+	value := os.Getenv(name)
+	if len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func getRequiredEnv(name string) string {
 	value := os.Getenv(name)
 	if len(value) == 0 {
@@ -35,36 +57,119 @@ func getRequiredEnv(name string) string {
 	return value
 }
 
-func createOrUpdateDNSRecord(
-	ctx context.Context,
-	client *armdns.RecordSetsClient,
-	resourceGroup string,
-	zoneName string,
-	recordName string,
-	ipAddress string,
-) (*armdns.RecordSet, error) {
-	resp, err := client.CreateOrUpdate(
-		ctx,
-		resourceGroup,
-		zoneName,
-		recordName,
-		armdns.RecordTypeA,
-		armdns.RecordSet{
-			Properties: &armdns.RecordSetProperties{
-				ARecords: []*armdns.ARecord{
-					{IPv4Address: &ipAddress},
-				},
-				TTL: to(3600),
-			},
-		},
-		nil,
-	)
+// legacyConfig builds a Config from the pre-subsystem AZURE_RELATIVE_RECORD_SET_NAME
+// / AZURE_RECORD_TYPES environment variables, so existing deployments that don't
+// supply AZURE_CONFIG_FILE keep working unchanged.
+func legacyConfig() (Config, error) {
+	recordNamesStr := getRequiredEnv("AZURE_RELATIVE_RECORD_SET_NAME")
+	recordTypesStr := getEnvOrDefault("AZURE_RECORD_TYPES", "A")
+
+	config := Config{}
+	for _, recordName := range strings.Split(recordNamesStr, ",") {
+		recordName = strings.TrimSpace(recordName)
+		for _, recordType := range strings.Split(recordTypesStr, ",") {
+			config[recordName] = append(config[recordName], records.Spec{Type: strings.TrimSpace(recordType)})
+		}
+	}
+	return config, nil
+}
+
+// resolveAzureContext determines the subscription/resource group to operate
+// on and the credential to authenticate with. By default it requires
+// AZURE_SUBSCRIPTION_ID/AZURE_RESOURCE_GROUP and uses
+// azidentity.NewDefaultAzureCredential. When AZURE_USE_IMDS=true it instead
+// queries the Instance Metadata Service (mirroring what the lego azure
+// provider does against defaultMetadataEndpoint) to fill in whichever of
+// those two are not already set via env, and authenticates with a managed
+// identity obtained through the same IMDS endpoint, so the tool runs on an
+// Azure VM with zero configuration beyond the zone/record names.
+func resolveAzureContext() (subscriptionID string, resourceGroupName string, cred azcore.TokenCredential, imdsInfo *imdsInstanceInfo, err error) {
+	if getEnvOrDefault("AZURE_USE_IMDS", "false") != "true" {
+		subscriptionID = getRequiredEnv("AZURE_SUBSCRIPTION_ID")
+		resourceGroupName = getRequiredEnv("AZURE_RESOURCE_GROUP")
+		cred, err = azidentity.NewDefaultAzureCredential(nil)
+		return
+	}
+
+	log.Println("AZURE_USE_IMDS=true, resolving subscription/resource group from the Instance Metadata Service")
+	imdsInfo, err = fetchIMDSInstanceInfo()
+	if err != nil {
+		err = fmt.Errorf("failed to query IMDS: %w", err)
+		return
+	}
+
+	subscriptionID = getEnvOrDefault("AZURE_SUBSCRIPTION_ID", imdsInfo.SubscriptionID)
+	resourceGroupName = getEnvOrDefault("AZURE_RESOURCE_GROUP", imdsInfo.ResourceGroup)
+	cred, err = azidentity.NewManagedIdentityCredential(nil)
+	return
+}
+
+// publicIP returns the detected public IP for the address family handlerType
+// publishes, caching each family's lookup so it's only fetched once per run.
+func publicIP(cache map[armdns.RecordType]string, handlerType armdns.RecordType) (string, error) {
+	if ip, ok := cache[handlerType]; ok {
+		return ip, nil
+	}
+
+	var ip string
+	var err error
+	switch handlerType {
+	case armdns.RecordTypeA:
+		ip, err = fetchPublicIp()
+	case armdns.RecordTypeAAAA:
+		ip, err = fetchPublicIPv6()
+	}
+	if err != nil {
+		return "", err
+	}
+	cache[handlerType] = ip
+	return ip, nil
+}
+
+// runACMEMode drives the pkg/acme DNS-01 provider as a one-shot "solver"
+// invocation: ACME_ACTION selects present or cleanup for the ACME_DOMAIN/
+// ACME_TOKEN/ACME_KEY_AUTH challenge supplied by the calling ACME client
+// (e.g. cert-manager or lego), so this tool can be wired in as that
+// client's DNS-01 hook instead of running its usual update loop. It's
+// selected with MODE=acme-dns01, independent of the update/delete-record/
+// list-records/purge-zone subcommands.
+func runACMEMode() {
+	subscriptionID, resourceGroupName, cred, _, err := resolveAzureContext()
+	if err != nil {
+		log.Fatal("Failed to resolve Azure context:", err)
+	}
+	zoneName := getRequiredEnv("AZURE_DNS_ZONE_NAME")
+
+	dnsClientFactory, err := armdns.NewClientFactory(subscriptionID, cred, nil)
+	if err != nil {
+		log.Fatal("Failed to create DNS client factory:", err)
+	}
+	recordSetsClient := dnsClientFactory.NewRecordSetsClient()
+
+	action := getRequiredEnv("ACME_ACTION")
+	domain := getRequiredEnv("ACME_DOMAIN")
+	token := getRequiredEnv("ACME_TOKEN")
+	keyAuth := getRequiredEnv("ACME_KEY_AUTH")
+
+	provider := acme.NewProvider(recordSetsClient, resourceGroupName, zoneName)
+
+	switch action {
+	case "present":
+		err = provider.Present(domain, token, keyAuth)
+	case "cleanup":
+		err = provider.CleanUp(domain, token, keyAuth)
+	default:
+		log.Fatalf("Unknown ACME_ACTION %q, expected \"present\" or \"cleanup\"", action)
+	}
 	if err != nil {
-		return nil, err
+		log.Fatalf("ACME %s failed for %s: %v", action, domain, err)
 	}
-	return &resp.RecordSet, nil
+	log.Printf("ACME %s succeeded for %s", action, domain)
 }
 
+// cleanup deletes resourceGroup entirely. It is deliberately only reachable
+// from the purge-zone subcommand, which requires an explicit --confirm flag
+// before calling it.
 func cleanup(
 	ctx context.Context,
 	client *armresources.ResourceGroupsClient,
@@ -82,79 +187,254 @@ func cleanup(
 	return nil
 }
 
-// Helper function to convert int32 to pointer
-func to(i int32) *int64 {
-	converted := int64(i)
-	return &converted
+func main() {
+	// MODE=acme-dns01 is an alternate run mode, orthogonal to the
+	// subcommands below.
+	if getEnvOrDefault("MODE", "") == "acme-dns01" {
+		runACMEMode()
+		return
+	}
+
+	// With no subcommand, fall back to update.
+	if len(os.Args) < 2 {
+		runUpdate(nil)
+		return
+	}
+
+	switch os.Args[1] {
+	case "update":
+		runUpdate(os.Args[2:])
+	case "delete-record":
+		runDeleteRecord(os.Args[2:])
+	case "list-records":
+		runListRecords(os.Args[2:])
+	case "purge-zone":
+		runPurgeZone(os.Args[2:])
+	default:
+		log.Fatalf("Unknown command %q; expected update, delete-record, list-records, or purge-zone", os.Args[1])
+	}
 }
 
-func main() {
-	// Get configuration from environment variables
-	subscriptionID := getRequiredEnv("AZURE_SUBSCRIPTION_ID")
-	resourceGroupName := getRequiredEnv("AZURE_RESOURCE_GROUP")
-	zoneName := getRequiredEnv("AZURE_DNS_ZONE_NAME")
-	recordNamesStr := getRequiredEnv("AZURE_RELATIVE_RECORD_SET_NAME")
+// runUpdate is the default verb: create or update every record described by
+// the config (AZURE_CONFIG_FILE, or the legacy env vars) against the
+// detected public IP. Unlike the old default behavior, it never deletes
+// the resource group; use the purge-zone subcommand for that.
+func runUpdate(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	fs.Parse(args)
 
-	// Split comma-separated record names
-	recordNames := strings.Split(recordNamesStr, ",")
+	zoneName := getRequiredEnv("AZURE_DNS_ZONE_NAME")
 
-	// Get credentials and create context
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	subscriptionID, resourceGroupName, cred, imdsInfo, err := resolveAzureContext()
 	if err != nil {
-		log.Fatal("Failed to get Azure credentials:", err)
+		log.Fatal("Failed to resolve Azure context:", err)
 	}
 	ctx := context.Background()
 
-	// Create client factories
-	resourcesClientFactory, err := armresources.NewClientFactory(subscriptionID, cred, nil)
+	dnsClientFactory, err := armdns.NewClientFactory(subscriptionID, cred, nil)
 	if err != nil {
-		log.Fatal("Failed to create resources client factory:", err)
+		log.Fatal("Failed to create DNS client factory:", err)
+	}
+	recordSetsClient := dnsClientFactory.NewRecordSetsClient()
+
+	// Load the record config: AZURE_CONFIG_FILE maps record names to handler
+	// specs (A, AAAA, PTR, CNAME, TXT, MX, SRV); without it we fall back to
+	// the legacy A/AAAA-only env vars.
+	var config Config
+	if configFile := os.Getenv("AZURE_CONFIG_FILE"); configFile != "" {
+		config, err = loadConfig(configFile)
+		if err != nil {
+			log.Fatal("Failed to load record config:", err)
+		}
+	} else {
+		config, err = legacyConfig()
+		if err != nil {
+			log.Fatal("Failed to build record config:", err)
+		}
+	}
+
+	// Load the IP state cache: if STATE_FILE is set, a record whose detected
+	// public IP matches the last one we published is skipped entirely.
+	stateFile := os.Getenv("STATE_FILE")
+	stateCache := StateCache{}
+	if stateFile != "" {
+		stateCache, err = loadStateCache(stateFile)
+		if err != nil {
+			log.Fatal("Failed to load state cache:", err)
+		}
+	}
+
+	// Create or update every record described by the config, dispatching to
+	// the handler its spec identifies.
+	ipCache := make(map[armdns.RecordType]string)
+	if imdsInfo != nil && imdsInfo.PublicIPv4 != "" {
+		log.Println("Using public IP from IMDS:", imdsInfo.PublicIPv4)
+		ipCache[armdns.RecordTypeA] = imdsInfo.PublicIPv4
+	}
+	stateChanged := false
+	for recordName, specs := range config {
+		for _, spec := range specs {
+			handler, err := records.NewHandler(spec)
+			if err != nil {
+				log.Printf("Skipping record %s: %v", recordName, err)
+				continue
+			}
+
+			ip := ""
+			if records.NeedsPublicIP(handler.Type()) {
+				ip, err = publicIP(ipCache, handler.Type())
+				if err != nil {
+					log.Printf("Failed to fetch public IP for record %s: %v", recordName, err)
+					continue
+				}
+
+				key := stateKey(zoneName, recordName, handler.Type())
+				if stateFile != "" && stateCache[key] == ip {
+					log.Printf("%s record %s unchanged (%s), skipping", handler.Type(), recordName, ip)
+					continue
+				}
+			}
+
+			log.Printf("Updating %s record %s...", handler.Type(), recordName)
+			resp, err := applyRecordSet(
+				ctx,
+				recordSetsClient,
+				resourceGroupName,
+				zoneName,
+				recordName,
+				handler.Type(),
+				handler.Build(ip),
+			)
+			if err != nil {
+				log.Printf("Failed to update %s record %s: %v", handler.Type(), recordName, err)
+				continue
+			}
+			log.Printf("%s record %s updated successfully: %s", handler.Type(), recordName, *resp.ID)
+
+			if records.NeedsPublicIP(handler.Type()) {
+				stateCache[stateKey(zoneName, recordName, handler.Type())] = ip
+				stateChanged = true
+			}
+		}
+	}
+
+	if stateFile != "" && stateChanged {
+		if err := stateCache.save(stateFile); err != nil {
+			log.Printf("Failed to save state cache: %v", err)
+		}
+	}
+}
+
+// runDeleteRecord deletes a single (zone, name, type) record set.
+func runDeleteRecord(args []string) {
+	fs := flag.NewFlagSet("delete-record", flag.ExitOnError)
+	zoneFlag := fs.String("zone", "", "DNS zone name (defaults to AZURE_DNS_ZONE_NAME)")
+	name := fs.String("record", "", "relative record name to delete (required)")
+	recordType := fs.String("type", "", "record type: A, AAAA, PTR, CNAME, TXT, MX, or SRV (required)")
+	fs.Parse(args)
+
+	if *name == "" || *recordType == "" {
+		log.Fatal("delete-record requires --record and --type")
+	}
+	zoneName := resolveZoneName(*zoneFlag)
+
+	subscriptionID, resourceGroupName, cred, _, err := resolveAzureContext()
+	if err != nil {
+		log.Fatal("Failed to resolve Azure context:", err)
 	}
 
 	dnsClientFactory, err := armdns.NewClientFactory(subscriptionID, cred, nil)
 	if err != nil {
 		log.Fatal("Failed to create DNS client factory:", err)
 	}
-
-	// Create specific clients
 	recordSetsClient := dnsClientFactory.NewRecordSetsClient()
-	resourceGroupClient := resourcesClientFactory.NewResourceGroupsClient()
 
-	// Fetch public IP once
-	ip, err := fetchPublicIp()
+	_, err = recordSetsClient.Delete(
+		context.Background(),
+		resourceGroupName,
+		zoneName,
+		*name,
+		armdns.RecordType(strings.ToUpper(*recordType)),
+		nil,
+	)
 	if err != nil {
-		log.Fatal("Failed to fetch public IP:", err)
+		log.Fatalf("Failed to delete %s record %s: %v", *recordType, *name, err)
 	}
-	log.Println("Detected public IP:", ip)
+	log.Printf("Deleted %s record %s in zone %s", *recordType, *name, zoneName)
+}
 
-	// Create or update multiple DNS records
-	for _, recordName := range recordNames {
-		recordName = strings.TrimSpace(recordName)
-		log.Printf("Updating DNS record %s...", recordName)
-
-		recordSet, err := createOrUpdateDNSRecord(
-			ctx,
-			recordSetsClient,
-			resourceGroupName,
-			zoneName,
-			recordName,
-			ip,
-		)
-		if err != nil {
-			log.Printf("Failed to update DNS record %s: %v", recordName, err)
-			continue
-		}
-		log.Printf("DNS record %s updated successfully: %s", recordName, *recordSet.ID)
+// runListRecords pages through every record set in the zone and prints it.
+func runListRecords(args []string) {
+	fs := flag.NewFlagSet("list-records", flag.ExitOnError)
+	zoneFlag := fs.String("zone", "", "DNS zone name (defaults to AZURE_DNS_ZONE_NAME)")
+	fs.Parse(args)
+
+	zoneName := resolveZoneName(*zoneFlag)
+
+	subscriptionID, resourceGroupName, cred, _, err := resolveAzureContext()
+	if err != nil {
+		log.Fatal("Failed to resolve Azure context:", err)
+	}
+
+	dnsClientFactory, err := armdns.NewClientFactory(subscriptionID, cred, nil)
+	if err != nil {
+		log.Fatal("Failed to create DNS client factory:", err)
 	}
+	recordSetsClient := dnsClientFactory.NewRecordSetsClient()
 
-	// In most cases, you don't want to delete the DNS zone, so likely keep this value set
-	keepResource := os.Getenv("KEEP_RESOURCE")
-	if keepResource != "true" {
-		log.Println("Cleaning up resources...")
-		err = cleanup(ctx, resourceGroupClient, resourceGroupName)
+	ctx := context.Background()
+	pager := recordSetsClient.NewListByDNSZonePager(resourceGroupName, zoneName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			log.Fatal("Failed to clean up resources:", err)
+			log.Fatal("Failed to list records:", err)
+		}
+		for _, recordSet := range page.Value {
+			ttl := int64(0)
+			if recordSet.Properties != nil && recordSet.Properties.TTL != nil {
+				ttl = *recordSet.Properties.TTL
+			}
+			fmt.Printf("%s\t%s\tTTL=%d\n", *recordSet.Name, *recordSet.Type, ttl)
 		}
-		log.Println("Resources cleaned up successfully.")
 	}
 }
+
+// runPurgeZone deletes the entire resource group backing the DNS zone. This
+// is the only place the old implicit "delete everything" behavior survives,
+// and it now requires --confirm=<zone-name> to make the blast radius explicit.
+func runPurgeZone(args []string) {
+	fs := flag.NewFlagSet("purge-zone", flag.ExitOnError)
+	zoneFlag := fs.String("zone", "", "DNS zone name (defaults to AZURE_DNS_ZONE_NAME)")
+	confirm := fs.String("confirm", "", "must equal the zone name, to confirm this destroys the whole resource group")
+	fs.Parse(args)
+
+	zoneName := resolveZoneName(*zoneFlag)
+	if *confirm != zoneName {
+		log.Fatalf("purge-zone requires --confirm=%s to proceed", zoneName)
+	}
+
+	subscriptionID, resourceGroupName, cred, _, err := resolveAzureContext()
+	if err != nil {
+		log.Fatal("Failed to resolve Azure context:", err)
+	}
+
+	resourcesClientFactory, err := armresources.NewClientFactory(subscriptionID, cred, nil)
+	if err != nil {
+		log.Fatal("Failed to create resources client factory:", err)
+	}
+	resourceGroupClient := resourcesClientFactory.NewResourceGroupsClient()
+
+	log.Printf("Purging resource group %s (zone %s)...", resourceGroupName, zoneName)
+	if err := cleanup(context.Background(), resourceGroupClient, resourceGroupName); err != nil {
+		log.Fatal("Failed to purge zone:", err)
+	}
+	log.Println("Zone purged successfully.")
+}
+
+// resolveZoneName returns flagValue if set, else AZURE_DNS_ZONE_NAME.
+func resolveZoneName(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return getRequiredEnv("AZURE_DNS_ZONE_NAME")
+}
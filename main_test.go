@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dewa55/updateAzureDNSrecords/records"
+)
+
+func TestLegacyConfigDualStack(t *testing.T) {
+	t.Setenv("AZURE_RELATIVE_RECORD_SET_NAME", "home,www")
+	t.Setenv("AZURE_RECORD_TYPES", "A,AAAA")
+	defer os.Unsetenv("AZURE_RELATIVE_RECORD_SET_NAME")
+	defer os.Unsetenv("AZURE_RECORD_TYPES")
+
+	config, err := legacyConfig()
+	if err != nil {
+		t.Fatalf("legacyConfig() returned error: %v", err)
+	}
+
+	for _, name := range []string{"home", "www"} {
+		specs := config[name]
+		if len(specs) != 2 {
+			t.Fatalf("config[%q] = %v, want 2 specs (A and AAAA)", name, specs)
+		}
+
+		handlersByType := map[string]bool{}
+		for _, spec := range specs {
+			handler, err := records.NewHandler(spec)
+			if err != nil {
+				t.Fatalf("building handler for %q spec %+v: %v", name, spec, err)
+			}
+			handlersByType[string(handler.Type())] = true
+		}
+		if !handlersByType["A"] || !handlersByType["AAAA"] {
+			t.Fatalf("config[%q] handlers = %v, want both A and AAAA", name, handlersByType)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package acme implements an ACME DNS-01 challenge provider backed by Azure
+// DNS, following the pattern of lego's azure provider: Present creates the
+// _acme-challenge TXT record for a domain and CleanUp removes it once the
+// CA has validated the challenge.
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+const (
+	challengePrefix = "_acme-challenge"
+	challengeTTL    = 60
+)
+
+// Provider presents and cleans up DNS-01 challenges as TXT records in a
+// single Azure DNS zone.
+type Provider struct {
+	client        *armdns.RecordSetsClient
+	resourceGroup string
+	zoneName      string
+}
+
+// NewProvider returns a Provider that manages challenge records in zoneName
+// using client.
+func NewProvider(client *armdns.RecordSetsClient, resourceGroup string, zoneName string) *Provider {
+	return &Provider{client: client, resourceGroup: resourceGroup, zoneName: zoneName}
+}
+
+// Present creates the _acme-challenge TXT record for domain so the ACME CA
+// can validate the DNS-01 challenge. token is accepted to match the
+// lego-style Present(domain, token, keyAuth) signature; the TXT value is
+// derived from keyAuth alone.
+func (p *Provider) Present(domain string, token string, keyAuth string) error {
+	recordName, err := p.challengeRecordName(domain)
+	if err != nil {
+		return err
+	}
+	value := challengeValue(keyAuth)
+	ttl := int64(challengeTTL)
+
+	_, err = p.client.CreateOrUpdate(
+		context.Background(),
+		p.resourceGroup,
+		p.zoneName,
+		recordName,
+		armdns.RecordTypeTXT,
+		armdns.RecordSet{
+			Properties: &armdns.RecordSetProperties{
+				TTL:        &ttl,
+				TxtRecords: []*armdns.TxtRecord{{Value: []*string{&value}}},
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("acme: failed to present challenge for %s: %w", domain, err)
+	}
+	return nil
+}
+
+// CleanUp deletes the _acme-challenge TXT record created by Present.
+func (p *Provider) CleanUp(domain string, token string, keyAuth string) error {
+	recordName, err := p.challengeRecordName(domain)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.client.Delete(context.Background(), p.resourceGroup, p.zoneName, recordName, armdns.RecordTypeTXT, nil)
+	if err != nil {
+		return fmt.Errorf("acme: failed to clean up challenge for %s: %w", domain, err)
+	}
+	return nil
+}
+
+// challengeRecordName returns the relative record name for domain's
+// challenge record within p.zoneName, e.g. "_acme-challenge" for domain
+// equal to the zone apex, or "_acme-challenge.www" for a "www" subdomain.
+func (p *Provider) challengeRecordName(domain string) (string, error) {
+	zone := strings.TrimSuffix(p.zoneName, ".")
+	domain = strings.TrimSuffix(domain, ".")
+
+	if domain == zone {
+		return challengePrefix, nil
+	}
+	if !strings.HasSuffix(domain, "."+zone) {
+		return "", fmt.Errorf("acme: domain %s is not part of zone %s", domain, zone)
+	}
+	subdomain := strings.TrimSuffix(domain, "."+zone)
+	return challengePrefix + "." + subdomain, nil
+}
+
+// challengeValue computes the DNS-01 TXT record value for keyAuth, per
+// RFC 8555 section 8.4: base64url(sha256(keyAuth)), no padding.
+func challengeValue(keyAuth string) string {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package acme
+
+import "testing"
+
+func TestChallengeRecordName(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    string
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{name: "apex", zone: "example.com", domain: "example.com", want: "_acme-challenge"},
+		{name: "apex trailing dot", zone: "example.com.", domain: "example.com.", want: "_acme-challenge"},
+		{name: "subdomain", zone: "example.com", domain: "www.example.com", want: "_acme-challenge.www"},
+		{name: "nested subdomain", zone: "example.com", domain: "a.b.example.com", want: "_acme-challenge.a.b"},
+		{name: "domain outside zone", zone: "example.com", domain: "example.org", wantErr: true},
+		{name: "domain that merely shares a suffix", zone: "example.com", domain: "notexample.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{zoneName: tt.zone}
+			got, err := p.challengeRecordName(tt.domain)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("challengeRecordName(%q) in zone %q = %q, want error", tt.domain, tt.zone, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("challengeRecordName(%q) in zone %q returned error: %v", tt.domain, tt.zone, err)
+			}
+			if got != tt.want {
+				t.Errorf("challengeRecordName(%q) in zone %q = %q, want %q", tt.domain, tt.zone, got, tt.want)
+			}
+		})
+	}
+}
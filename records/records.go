@@ -0,0 +1,212 @@
+// Package records maps a config-driven record specification onto the
+// armdns.RecordSet the Azure DNS API expects, so main can drive
+// client.CreateOrUpdate generically instead of hard-coding A records.
+package records
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+// Spec is the config-file shape for a single record: which handler to build
+// and the handler-specific fields it needs. Fields that don't apply to a
+// given Type are left zero.
+type Spec struct {
+	Type     string   `json:"type" yaml:"type"`
+	TTL      int64    `json:"ttl" yaml:"ttl"`
+	Value    string   `json:"value,omitempty" yaml:"value,omitempty"`
+	Values   []string `json:"values,omitempty" yaml:"values,omitempty"`
+	Target   string   `json:"target,omitempty" yaml:"target,omitempty"`
+	Priority int32    `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Weight   int32    `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Port     int32    `json:"port,omitempty" yaml:"port,omitempty"`
+}
+
+// RecordHandler builds the armdns.RecordSet for one record name and reports
+// which armdns.RecordType it publishes as. Implementations that publish a
+// detected IP (A, AAAA) use the ip argument; static handlers ignore it.
+type RecordHandler interface {
+	Build(ip string) armdns.RecordSet
+	Type() armdns.RecordType
+}
+
+// NewHandler builds the RecordHandler described by spec, defaulting TTL to
+// 3600 seconds when unset.
+func NewHandler(spec Spec) (RecordHandler, error) {
+	ttl := spec.TTL
+	if ttl == 0 {
+		ttl = 3600
+	}
+
+	switch strings.ToUpper(spec.Type) {
+	case "A":
+		return &aHandler{ttl: ttl}, nil
+	case "AAAA":
+		return &aaaaHandler{ttl: ttl}, nil
+	case "PTR":
+		if spec.Target == "" {
+			return nil, fmt.Errorf("PTR record requires target")
+		}
+		return &ptrHandler{ttl: ttl, target: spec.Target}, nil
+	case "CNAME":
+		if spec.Target == "" {
+			return nil, fmt.Errorf("CNAME record requires target")
+		}
+		return &cnameHandler{ttl: ttl, target: spec.Target}, nil
+	case "TXT":
+		if len(spec.Values) == 0 {
+			return nil, fmt.Errorf("TXT record requires at least one value")
+		}
+		return &txtHandler{ttl: ttl, values: spec.Values}, nil
+	case "MX":
+		if spec.Target == "" {
+			return nil, fmt.Errorf("MX record requires target")
+		}
+		return &mxHandler{ttl: ttl, preference: spec.Priority, exchange: spec.Target}, nil
+	case "SRV":
+		if spec.Target == "" {
+			return nil, fmt.Errorf("SRV record requires target")
+		}
+		return &srvHandler{
+			ttl:      ttl,
+			priority: spec.Priority,
+			weight:   spec.Weight,
+			port:     spec.Port,
+			target:   spec.Target,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported record type: %s", spec.Type)
+	}
+}
+
+type aHandler struct{ ttl int64 }
+
+func (h *aHandler) Type() armdns.RecordType { return armdns.RecordTypeA }
+
+func (h *aHandler) Build(ip string) armdns.RecordSet {
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:      &h.ttl,
+			ARecords: []*armdns.ARecord{{IPv4Address: &ip}},
+		},
+	}
+}
+
+type aaaaHandler struct{ ttl int64 }
+
+func (h *aaaaHandler) Type() armdns.RecordType { return armdns.RecordTypeAAAA }
+
+func (h *aaaaHandler) Build(ip string) armdns.RecordSet {
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:         &h.ttl,
+			AaaaRecords: []*armdns.AaaaRecord{{IPv6Address: &ip}},
+		},
+	}
+}
+
+// ptrHandler mirrors the reverse-lookup record support added to the
+// terraform-provider-azurerm azurerm_dns_ptr_record resource: a PTR record
+// is a static pointer from an in-addr.arpa/ip6.arpa name to a target FQDN.
+type ptrHandler struct {
+	ttl    int64
+	target string
+}
+
+func (h *ptrHandler) Type() armdns.RecordType { return armdns.RecordTypePTR }
+
+func (h *ptrHandler) Build(string) armdns.RecordSet {
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:        &h.ttl,
+			PtrRecords: []*armdns.PtrRecord{{Ptrdname: &h.target}},
+		},
+	}
+}
+
+type cnameHandler struct {
+	ttl    int64
+	target string
+}
+
+func (h *cnameHandler) Type() armdns.RecordType { return armdns.RecordTypeCNAME }
+
+func (h *cnameHandler) Build(string) armdns.RecordSet {
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:         &h.ttl,
+			CnameRecord: &armdns.CnameRecord{Cname: &h.target},
+		},
+	}
+}
+
+type txtHandler struct {
+	ttl    int64
+	values []string
+}
+
+func (h *txtHandler) Type() armdns.RecordType { return armdns.RecordTypeTXT }
+
+func (h *txtHandler) Build(string) armdns.RecordSet {
+	segments := make([]*string, len(h.values))
+	for i := range h.values {
+		segments[i] = &h.values[i]
+	}
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:        &h.ttl,
+			TxtRecords: []*armdns.TxtRecord{{Value: segments}},
+		},
+	}
+}
+
+type mxHandler struct {
+	ttl        int64
+	preference int32
+	exchange   string
+}
+
+func (h *mxHandler) Type() armdns.RecordType { return armdns.RecordTypeMX }
+
+func (h *mxHandler) Build(string) armdns.RecordSet {
+	preference := int32(h.preference)
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL: &h.ttl,
+			MxRecords: []*armdns.MxRecord{
+				{Preference: &preference, Exchange: &h.exchange},
+			},
+		},
+	}
+}
+
+type srvHandler struct {
+	ttl      int64
+	priority int32
+	weight   int32
+	port     int32
+	target   string
+}
+
+func (h *srvHandler) Type() armdns.RecordType { return armdns.RecordTypeSRV }
+
+func (h *srvHandler) Build(string) armdns.RecordSet {
+	priority, weight, port := h.priority, h.weight, h.port
+	return armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL: &h.ttl,
+			SrvRecords: []*armdns.SrvRecord{
+				{Priority: &priority, Weight: &weight, Port: &port, Target: &h.target},
+			},
+		},
+	}
+}
+
+// NeedsPublicIP reports whether handlerType requires a freshly-detected
+// public IP address to build its record set (the A/AAAA families), as
+// opposed to a fully static record (PTR, CNAME, TXT, MX, SRV).
+func NeedsPublicIP(handlerType armdns.RecordType) bool {
+	return handlerType == armdns.RecordTypeA || handlerType == armdns.RecordTypeAAAA
+}
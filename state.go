@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+// StateCache remembers the last successfully-published IP for each
+// zone+record+type, so a run whose detected public IP hasn't changed since
+// the last one can skip the Azure API call entirely. It's only meaningful
+// for the IP-based handlers (A/AAAA); static records aren't cached.
+type StateCache map[string]string
+
+// stateKey builds the StateCache key for a given zone, record name and type.
+func stateKey(zoneName string, recordName string, recordType armdns.RecordType) string {
+	return zoneName + "/" + recordName + "/" + string(recordType)
+}
+
+// loadStateCache reads the state file at path. A missing file is not an
+// error; it just means there's no prior state yet.
+func loadStateCache(path string) (StateCache, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return StateCache{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cache := StateCache{}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// save writes the cache to path as JSON.
+func (c StateCache) save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
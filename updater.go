@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+// etagRetryBackoff is a var, not a const, so tests can shrink it.
+var etagRetryBackoff = 2 * time.Second
+
+// applyRecordSet creates or updates recordSet at (zoneName, recordName,
+// recordType), guarding against concurrent mutators with an Etag
+// conditional write: it first fetches the record set's current Etag (if
+// any) and sends it as IfMatch, so a conflicting writer causes a 412
+// Precondition Failed instead of a silent clobber. On 412 it backs off,
+// re-fetches the Etag, and retries once.
+func applyRecordSet(
+	ctx context.Context,
+	client *armdns.RecordSetsClient,
+	resourceGroupName string,
+	zoneName string,
+	recordName string,
+	recordType armdns.RecordType,
+	recordSet armdns.RecordSet,
+) (*armdns.RecordSet, error) {
+	etag, err := currentEtag(ctx, client, resourceGroupName, zoneName, recordName, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.CreateOrUpdate(
+		ctx, resourceGroupName, zoneName, recordName, recordType, recordSet,
+		&armdns.RecordSetsClientCreateOrUpdateOptions{IfMatch: etag},
+	)
+	if isPreconditionFailed(err) {
+		time.Sleep(etagRetryBackoff)
+		etag, etagErr := currentEtag(ctx, client, resourceGroupName, zoneName, recordName, recordType)
+		if etagErr != nil {
+			return nil, etagErr
+		}
+		resp, err = client.CreateOrUpdate(
+			ctx, resourceGroupName, zoneName, recordName, recordType, recordSet,
+			&armdns.RecordSetsClientCreateOrUpdateOptions{IfMatch: etag},
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp.RecordSet, nil
+}
+
+// currentEtag fetches the record set's current Etag, returning nil if the
+// record doesn't exist yet (a plain create, with no conditional header).
+func currentEtag(
+	ctx context.Context,
+	client *armdns.RecordSetsClient,
+	resourceGroupName string,
+	zoneName string,
+	recordName string,
+	recordType armdns.RecordType,
+) (*string, error) {
+	resp, err := client.Get(ctx, resourceGroupName, zoneName, recordName, recordType, nil)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Etag, nil
+}
+
+func isPreconditionFailed(err error) bool {
+	return responseStatusCode(err) == http.StatusPreconditionFailed
+}
+
+func isNotFound(err error) bool {
+	return responseStatusCode(err) == http.StatusNotFound
+}
+
+func responseStatusCode(err error) int {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode
+	}
+	return 0
+}
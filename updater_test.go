@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	armdnsfake "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns/fake"
+)
+
+// newFakeRecordSetsClient wires an armdns.RecordSetsClient to srv instead of
+// the real Azure DNS API, for exercising applyRecordSet's Etag/retry logic.
+func newFakeRecordSetsClient(t *testing.T, srv *armdnsfake.RecordSetsServer) *armdns.RecordSetsClient {
+	t.Helper()
+	client, err := armdns.NewRecordSetsClient("sub", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: armdnsfake.NewRecordSetsServerTransport(srv),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build fake RecordSetsClient: %v", err)
+	}
+	return client
+}
+
+func TestApplyRecordSetRetriesOnceOn412(t *testing.T) {
+	orig := etagRetryBackoff
+	etagRetryBackoff = time.Millisecond
+	defer func() { etagRetryBackoff = orig }()
+
+	getCalls := 0
+	createCalls := 0
+
+	srv := &armdnsfake.RecordSetsServer{
+		Get: func(ctx context.Context, resourceGroupName, zoneName, relativeRecordSetName string, recordType armdns.RecordType, options *armdns.RecordSetsClientGetOptions) (resp azfake.Responder[armdns.RecordSetsClientGetResponse], errResp azfake.ErrorResponder) {
+			getCalls++
+			result := armdns.RecordSetsClientGetResponse{
+				RecordSet: armdns.RecordSet{
+					Etag:       to.Ptr("etag-before"),
+					Properties: &armdns.RecordSetProperties{TTL: to.Ptr(int64(60))},
+				},
+			}
+			if getCalls > 1 {
+				result.Etag = to.Ptr("etag-after")
+			}
+			resp.SetResponse(http.StatusOK, result, nil)
+			return
+		},
+		CreateOrUpdate: func(ctx context.Context, resourceGroupName, zoneName, relativeRecordSetName string, recordType armdns.RecordType, parameters armdns.RecordSet, options *armdns.RecordSetsClientCreateOrUpdateOptions) (resp azfake.Responder[armdns.RecordSetsClientCreateOrUpdateResponse], errResp azfake.ErrorResponder) {
+			createCalls++
+			if createCalls == 1 {
+				errResp.SetResponseError(http.StatusPreconditionFailed, "PreconditionFailed")
+				return
+			}
+			result := armdns.RecordSetsClientCreateOrUpdateResponse{
+				RecordSet: armdns.RecordSet{
+					ID: to.Ptr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/dnsZones/example.com/A/www"),
+				},
+			}
+			resp.SetResponse(http.StatusOK, result, nil)
+			return
+		},
+	}
+
+	client := newFakeRecordSetsClient(t, srv)
+	recordSet, err := applyRecordSet(
+		context.Background(),
+		client,
+		"rg",
+		"example.com",
+		"www",
+		armdns.RecordTypeA,
+		armdns.RecordSet{
+			Properties: &armdns.RecordSetProperties{
+				TTL:      to.Ptr(int64(60)),
+				ARecords: []*armdns.ARecord{{IPv4Address: to.Ptr("1.2.3.4")}},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("applyRecordSet returned error: %v", err)
+	}
+	if recordSet.ID == nil || *recordSet.ID == "" {
+		t.Fatalf("applyRecordSet returned record set with no ID")
+	}
+	if getCalls != 2 {
+		t.Errorf("Get calls = %d, want 2 (initial Etag fetch + retry re-fetch)", getCalls)
+	}
+	if createCalls != 2 {
+		t.Errorf("CreateOrUpdate calls = %d, want 2 (initial 412 + retry success)", createCalls)
+	}
+}
+
+func TestApplyRecordSetDoesNotRetryMoreThanOnce(t *testing.T) {
+	orig := etagRetryBackoff
+	etagRetryBackoff = time.Millisecond
+	defer func() { etagRetryBackoff = orig }()
+
+	createCalls := 0
+
+	srv := &armdnsfake.RecordSetsServer{
+		Get: func(ctx context.Context, resourceGroupName, zoneName, relativeRecordSetName string, recordType armdns.RecordType, options *armdns.RecordSetsClientGetOptions) (resp azfake.Responder[armdns.RecordSetsClientGetResponse], errResp azfake.ErrorResponder) {
+			result := armdns.RecordSetsClientGetResponse{
+				RecordSet: armdns.RecordSet{Etag: to.Ptr("etag")},
+			}
+			resp.SetResponse(http.StatusOK, result, nil)
+			return
+		},
+		CreateOrUpdate: func(ctx context.Context, resourceGroupName, zoneName, relativeRecordSetName string, recordType armdns.RecordType, parameters armdns.RecordSet, options *armdns.RecordSetsClientCreateOrUpdateOptions) (resp azfake.Responder[armdns.RecordSetsClientCreateOrUpdateResponse], errResp azfake.ErrorResponder) {
+			createCalls++
+			errResp.SetResponseError(http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		},
+	}
+
+	client := newFakeRecordSetsClient(t, srv)
+	_, err := applyRecordSet(
+		context.Background(),
+		client,
+		"rg",
+		"example.com",
+		"www",
+		armdns.RecordTypeA,
+		armdns.RecordSet{
+			Properties: &armdns.RecordSetProperties{
+				TTL:      to.Ptr(int64(60)),
+				ARecords: []*armdns.ARecord{{IPv4Address: to.Ptr("1.2.3.4")}},
+			},
+		},
+	)
+	if err == nil {
+		t.Fatal("applyRecordSet succeeded, want error after persistent 412")
+	}
+	if createCalls != 2 {
+		t.Errorf("CreateOrUpdate calls = %d, want exactly 2 (no retry loop beyond the single retry)", createCalls)
+	}
+}